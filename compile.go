@@ -0,0 +1,179 @@
+package skyhook
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/skylark/syntax"
+)
+
+// defaultCompileCacheSize is the number of compiled scripts Run keeps around
+// by default; override it with WithCacheSize.
+const defaultCompileCacheSize = 32
+
+// Compiled is a plugin script that has been resolved against a Skyhook's
+// dirs once, so Run can be called repeatedly without re-reading the file
+// from disk or re-searching dirs on every call.
+//
+// Compiled does NOT skip re-parsing: github.com/google/skylark's ExecFile
+// takes raw source and always parses and resolves it internally, and this
+// package doesn't vendor or fork skylark to call a lower-level, AST-level
+// entry point. So despite "compile" in the name, Compiled.Run still calls
+// skylark.ExecFile on the cached bytes every time -- see BenchmarkRun vs
+// BenchmarkCompiledRun for what caching the resolve+read actually saves,
+// and for how little: parsing, not I/O, dominates most scripts.
+type Compiled struct {
+	s    Skyhook
+	path string
+	data []byte
+}
+
+// Compile resolves filename against s.dirs, as Run does, parses it once to
+// catch syntax errors early, and returns a Compiled that can be run
+// repeatedly with Run/RunContext, skipping the repeated dir search and disk
+// read Run would otherwise do.  See the Compiled doc for what this does and
+// does not save.
+func (s Skyhook) Compile(filename string) (*Compiled, error) {
+	path, data, err := s.resolve(filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := syntax.Parse(path, data, 0); err != nil {
+		return nil, err
+	}
+	return &Compiled{s: s, path: path, data: data}, nil
+}
+
+// Run executes the compiled script with the given args, as Skyhook.Run does.
+func (c *Compiled) Run(args map[string]interface{}) (map[string]interface{}, error) {
+	return c.RunContext(context.Background(), args)
+}
+
+// RunContext behaves like Run, but aborts if ctx is canceled, as
+// Skyhook.RunContext does.
+func (c *Compiled) RunContext(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+	return c.s.exec(ctx, c.path, c.data, args)
+}
+
+// WithCacheSize sets how many compiled scripts Run keeps in its in-memory
+// cache, keyed by resolved path, mtime, and size.  A size of 0 disables the
+// cache, so every Run re-resolves and re-reads the file.
+func WithCacheSize(n int) SkyhookOption {
+	return func(s *Skyhook) {
+		s.compiled = newCompiledCache(n)
+	}
+}
+
+// compiledFor returns a Compiled for filename, transparently reusing a
+// cached one if filename hasn't changed on disk since it was compiled.
+func (s Skyhook) compiledFor(filename string) (*Compiled, error) {
+	for _, d := range s.dirs {
+		p := filepath.Join(d, filename)
+		fi, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		key := compileKey{path: p, modTime: fi.ModTime().UnixNano(), size: fi.Size()}
+		if c, ok := s.compiled.get(key); ok {
+			return c, nil
+		}
+		break
+	}
+
+	path, data, err := s.resolve(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	key := compileKey{path: path}
+	if fi, err := os.Stat(path); err == nil {
+		key.modTime = fi.ModTime().UnixNano()
+		key.size = fi.Size()
+	}
+
+	if c, ok := s.compiled.get(key); ok {
+		return c, nil
+	}
+
+	if _, err := syntax.Parse(path, data, 0); err != nil {
+		return nil, err
+	}
+	c := &Compiled{s: s, path: path, data: data}
+	s.compiled.add(key, c)
+	return c, nil
+}
+
+// compileKey identifies a cached Compiled by its resolved path and the
+// mtime/size observed when it was compiled, so a file edited on disk is
+// transparently recompiled rather than served stale.
+type compileKey struct {
+	path    string
+	modTime int64
+	size    int64
+}
+
+// compiledCache is a fixed-size LRU cache of Compiled scripts, keyed by
+// compileKey.
+type compiledCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	index map[compileKey]*list.Element
+}
+
+type compiledCacheEntry struct {
+	key compileKey
+	c   *Compiled
+}
+
+func newCompiledCache(size int) *compiledCache {
+	return &compiledCache{
+		size:  size,
+		ll:    list.New(),
+		index: make(map[compileKey]*list.Element),
+	}
+}
+
+func (c *compiledCache) get(key compileKey) (*Compiled, bool) {
+	if c.size <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.index[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*compiledCacheEntry).c, true
+}
+
+func (c *compiledCache) add(key compileKey, compiled *Compiled) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.index[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*compiledCacheEntry).c = compiled
+		return
+	}
+
+	e := c.ll.PushFront(&compiledCacheEntry{key: key, c: compiled})
+	c.index[key] = e
+
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.index, oldest.Value.(*compiledCacheEntry).key)
+	}
+}
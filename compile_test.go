@@ -0,0 +1,82 @@
+package skyhook
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// benchScript is a small, representative plugin: enough parsing work to be
+// non-trivial, negligible args/return handling.
+const benchScript = `
+def f():
+    total = 0
+    for i in range(100):
+        total += i
+    return total
+
+total = f()
+`
+
+func setupBenchSkyhook(b *testing.B, opts ...SkyhookOption) (Skyhook, string) {
+	b.Helper()
+	dir, err := ioutil.TempDir("", "skyhook-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	writeScript(b, dir, "bench.sky", benchScript)
+	return New([]string{dir}, opts...), dir
+}
+
+// BenchmarkRun re-resolves, re-reads, and re-parses bench.sky on every
+// call, with Run's compile cache disabled.
+func BenchmarkRun(b *testing.B) {
+	s, dir := setupBenchSkyhook(b, WithCacheSize(0))
+	defer os.RemoveAll(dir)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Run("bench.sky", nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCompiledRun skips the repeated dir search and disk read that
+// BenchmarkRun pays for every call; it still re-parses on every call (see
+// the Compiled doc comment), so the gap between the two benchmarks is the
+// actual, honest saving from this request -- not a full "compile once"
+// speedup.
+func BenchmarkCompiledRun(b *testing.B) {
+	s, dir := setupBenchSkyhook(b)
+	defer os.RemoveAll(dir)
+
+	c, err := s.Compile("bench.sky")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Run(nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRunCached exercises Run's transparent compile cache (the default
+// WithCacheSize), which should track BenchmarkCompiledRun once the cache is
+// warm.
+func BenchmarkRunCached(b *testing.B) {
+	s, dir := setupBenchSkyhook(b)
+	defer os.RemoveAll(dir)
+
+	if _, err := s.Run("bench.sky", nil); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Run("bench.sky", nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
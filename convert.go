@@ -0,0 +1,357 @@
+package skyhook
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/skylark"
+)
+
+// DefaultStructTag is the struct tag key consulted, by default, when
+// converting Go struct fields to and from skylark attribute names.  A field
+// can be renamed with `skylark:"name"` or excluded entirely with
+// `skylark:"-"`.
+const DefaultStructTag = "skylark"
+
+// ToValueWithTag behaves like ToValue, but uses tag instead of
+// DefaultStructTag when converting struct fields.  Unlike ToValue, it
+// converts every non-skylark value by reflection, so a map[interface{}]bool
+// becomes a dict of bools rather than a set.
+func ToValueWithTag(v interface{}, tag string) (skylark.Value, error) {
+	if val, ok := v.(skylark.Value); ok {
+		return val, nil
+	}
+	return toValueReflect(v, tag, StructModeSnapshot)
+}
+
+// toValueReflect is the reflection-based fallback used by ToValue once its
+// fast-path type switch has been exhausted.  mode controls how a struct or
+// pointer-to-struct is converted; see StructMode.
+func toValueReflect(v interface{}, tag string, mode StructMode) (skylark.Value, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return skylark.None, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return skylark.MakeInt64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return skylark.MakeUint64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return skylark.Float(rv.Float()), nil
+	case reflect.Bool:
+		return skylark.Bool(rv.Bool()), nil
+	case reflect.String:
+		return skylark.String(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		n := rv.Len()
+		vals := make([]skylark.Value, n)
+		for i := 0; i < n; i++ {
+			val, err := toValueReflect(rv.Index(i).Interface(), tag, mode)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = val
+		}
+		return skylark.NewList(vals), nil
+	case reflect.Map:
+		dict := &skylark.Dict{}
+		for _, k := range rv.MapKeys() {
+			key, err := toValueReflect(k.Interface(), tag, mode)
+			if err != nil {
+				return nil, err
+			}
+			val, err := toValueReflect(rv.MapIndex(k).Interface(), tag, mode)
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.Set(key, val); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	case reflect.Func:
+		return WrapFunc(rv)
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return skylark.None, nil
+		}
+		if rv.Elem().Kind() == reflect.Struct {
+			return structToValue(rv, tag, mode)
+		}
+		return toValueReflect(rv.Elem().Interface(), tag, mode)
+	case reflect.Struct:
+		return structToValue(rv, tag, mode)
+	}
+
+	return nil, fmt.Errorf("type %T is not a supported skylark type", v)
+}
+
+// fromValueReflect is the reflection-based fallback used by FromValue once
+// its type switch over the core skylark types has been exhausted.
+func fromValueReflect(v skylark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case *goStruct:
+		return v.v.Interface(), nil
+	case *skylark.Builtin:
+		if fn, ok := v.Receiver().(*goFunc); ok {
+			return fn.fn.Interface(), nil
+		}
+	case skylark.NoneType:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("type %T is not a supported skylark type", v)
+}
+
+// structField describes how a Go struct field is exposed to skylark.
+func structField(tag string, f reflect.StructField) (name string, ok bool) {
+	if f.PkgPath != "" {
+		// unexported
+		return "", false
+	}
+	name = f.Name
+	if t, hasTag := f.Tag.Lookup(tag); hasTag {
+		if t == "-" {
+			return "", false
+		}
+		if t != "" {
+			name = t
+		}
+	}
+	return name, true
+}
+
+// goStruct wraps a Go struct (or pointer to one) so it can be used directly
+// as a skylark.Value: exported fields become attributes, named with tag (see
+// structField), and exported methods become callable builtins.
+type goStruct struct {
+	v   reflect.Value // struct or pointer-to-struct
+	tag string
+}
+
+var (
+	_ skylark.Value    = (*goStruct)(nil)
+	_ skylark.HasAttrs = (*goStruct)(nil)
+)
+
+func (g *goStruct) structValue() reflect.Value {
+	if g.v.Kind() == reflect.Ptr {
+		return g.v.Elem()
+	}
+	return g.v
+}
+
+func (g *goStruct) String() string {
+	return fmt.Sprintf("%v", g.v.Interface())
+}
+
+func (g *goStruct) Type() string {
+	return g.structValue().Type().String()
+}
+
+func (g *goStruct) Freeze() {}
+
+func (g *goStruct) Truth() skylark.Bool {
+	return skylark.True
+}
+
+func (g *goStruct) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", g.Type())
+}
+
+func (g *goStruct) Attr(name string) (skylark.Value, error) {
+	sv := g.structValue()
+	st := sv.Type()
+
+	for i := 0; i < st.NumField(); i++ {
+		fieldName, ok := structField(g.tag, st.Field(i))
+		if ok && fieldName == name {
+			return toValueReflect(sv.Field(i).Interface(), g.tag, StructModeLive)
+		}
+	}
+
+	if m := g.v.MethodByName(name); m.IsValid() {
+		return WrapFunc(m)
+	}
+
+	return nil, nil
+}
+
+func (g *goStruct) AttrNames() []string {
+	sv := g.structValue()
+	st := sv.Type()
+
+	names := make([]string, 0, st.NumField())
+	for i := 0; i < st.NumField(); i++ {
+		if name, ok := structField(g.tag, st.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	for i := 0; i < g.v.Type().NumMethod(); i++ {
+		names = append(names, g.v.Type().Method(i).Name)
+	}
+	return names
+}
+
+// goFunc is the receiver attached to the *skylark.Builtin returned by
+// WrapFunc, so FromValue can recover the original Go function.  It
+// implements skylark.Value only so it can be used as a Builtin receiver; it
+// is never exposed to scripts directly.
+type goFunc struct {
+	fn reflect.Value
+}
+
+var _ skylark.Value = (*goFunc)(nil)
+
+func (g *goFunc) String() string        { return fmt.Sprintf("<go func %s>", g.fn.Type()) }
+func (g *goFunc) Type() string          { return g.fn.Type().String() }
+func (g *goFunc) Freeze()               {}
+func (g *goFunc) Truth() skylark.Bool   { return skylark.True }
+func (g *goFunc) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: %s", g.Type()) }
+
+// WrapFunc wraps an arbitrary Go func as a *skylark.Builtin.  fn may be
+// either a reflect.Value or any func value; on call, each skylark argument
+// is converted to the corresponding Go parameter type with the reverse of
+// ToValue, the func is invoked via reflect.Call, and the results are
+// converted back: multiple returns become a tuple, and a trailing error
+// return is propagated as a skylark error rather than a value.
+func WrapFunc(fn interface{}) (*skylark.Builtin, error) {
+	rv, ok := fn.(reflect.Value)
+	if !ok {
+		rv = reflect.ValueOf(fn)
+	}
+	if rv.Kind() != reflect.Func {
+		return nil, fmt.Errorf("WrapFunc: %T is not a func", fn)
+	}
+	ft := rv.Type()
+	name := "go-func"
+
+	gf := &goFunc{fn: rv}
+	b := skylark.NewBuiltin(name, func(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf("%s: keyword arguments are not supported", name)
+		}
+		if ft.IsVariadic() {
+			if len(args) < ft.NumIn()-1 {
+				return nil, fmt.Errorf("%s: want at least %d arguments, got %d", name, ft.NumIn()-1, len(args))
+			}
+		} else if len(args) != ft.NumIn() {
+			return nil, fmt.Errorf("%s: want %d arguments, got %d", name, ft.NumIn(), len(args))
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var paramType reflect.Type
+			if ft.IsVariadic() && i >= ft.NumIn()-1 {
+				paramType = ft.In(ft.NumIn() - 1).Elem()
+			} else {
+				paramType = ft.In(i)
+			}
+			goArg, err := FromValue(arg)
+			if err != nil {
+				return nil, err
+			}
+			argVal, err := convertTo(goArg, paramType)
+			if err != nil {
+				return nil, fmt.Errorf("%s: argument %d: %v", name, i, err)
+			}
+			in[i] = argVal
+		}
+
+		out := rv.Call(in)
+		return wrapResults(out)
+	})
+	return b.BindReceiver(gf), nil
+}
+
+// convertTo converts goVal -- a value produced by FromValue, so composite
+// types arrive as []interface{} or map[interface{}]interface{} rather than
+// the concrete slice/map type a Go parameter wants -- into a reflect.Value
+// assignable to t, recursing into elements so e.g. []interface{} containing
+// strings converts to a []string parameter instead of panicking in
+// reflect.Call.
+func convertTo(goVal interface{}, t reflect.Type) (reflect.Value, error) {
+	if goVal == nil {
+		return reflect.Zero(t), nil
+	}
+	v := reflect.ValueOf(goVal)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if v.Type().ConvertibleTo(t) {
+		return v.Convert(t), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		elems, ok := goVal.([]interface{})
+		if !ok {
+			break
+		}
+		out := reflect.MakeSlice(reflect.SliceOf(t.Elem()), len(elems), len(elems))
+		for i, e := range elems {
+			ev, err := convertTo(e, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(ev)
+		}
+		if t.Kind() == reflect.Array {
+			arr := reflect.New(t).Elem()
+			reflect.Copy(arr, out)
+			return arr, nil
+		}
+		return out, nil
+	case reflect.Map:
+		m, ok := goVal.(map[interface{}]interface{})
+		if !ok {
+			break
+		}
+		out := reflect.MakeMapWithSize(t, len(m))
+		for k, e := range m {
+			kv, err := convertTo(k, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			ev, err := convertTo(e, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(kv, ev)
+		}
+		return out, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot convert %T to %s", goVal, t)
+}
+
+// wrapResults converts the []reflect.Value returned by reflect.Call back into
+// a skylark.Value, honoring the convention that a trailing error result
+// reports failure rather than being converted.
+func wrapResults(out []reflect.Value) (skylark.Value, error) {
+	n := len(out)
+	if n > 0 && out[n-1].Type() == reflect.TypeOf((*error)(nil)).Elem() {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:n-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return skylark.None, nil
+	case 1:
+		return ToValue(out[0].Interface())
+	default:
+		vals := make([]skylark.Value, len(out))
+		for i, o := range out {
+			val, err := ToValue(o.Interface())
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = val
+		}
+		return skylark.Tuple(vals), nil
+	}
+}
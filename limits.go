@@ -0,0 +1,67 @@
+package skyhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/skylark"
+)
+
+// tickBuiltinName is the global a script must call periodically (e.g. once
+// per loop iteration: `for x in big_range: _tick()`) for WithMaxSteps,
+// WithMaxWallTime, and RunContext cancellation to take effect.
+//
+// This package still imports the pre-rename github.com/google/skylark,
+// which predates Thread.Cancel/SetMaxExecutionSteps (those were added to
+// the project only after its go.starlark.net rename), so there is no
+// interpreter-level hook to preempt a running script. Limits are therefore
+// enforced cooperatively: _tick is injected into a script's globals
+// whenever a limit is configured, and it is the only point where a step
+// count or deadline is actually checked. A script whose hot loop never
+// calls _tick() cannot be interrupted once skylark.ExecFile starts -- treat
+// that as a script disabling its own limits, and reject such scripts by
+// convention/lint rather than relying on this package to stop them.
+const tickBuiltinName = "_tick"
+
+// newTickBuiltin returns the _tick() builtin for a single Run/RunContext
+// call. Each call increments a step counter private to that call and checks
+// it against maxSteps (0 meaning no step limit), then checks ctx, which
+// carries any WithMaxWallTime deadline composed with the caller's context.
+func newTickBuiltin(ctx context.Context, maxSteps uint64) *skylark.Builtin {
+	var steps uint64
+	return skylark.NewBuiltin(tickBuiltinName, func(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+		steps++
+		if maxSteps > 0 && steps > maxSteps {
+			return nil, fmt.Errorf("skyhook: exceeded max execution steps (%d)", maxSteps)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		return skylark.None, nil
+	})
+}
+
+// WithMaxSteps bounds how many times a script may call _tick() before it is
+// aborted with an error. It guards against infinite loops in untrusted
+// scripts that cooperate by calling _tick(); see tickBuiltinName for the
+// caveat that a script which never calls _tick() isn't bounded by this at
+// all. A value of 0 (the default) means unlimited.
+func WithMaxSteps(n uint64) SkyhookOption {
+	return func(s *Skyhook) {
+		s.maxSteps = n
+	}
+}
+
+// WithMaxWallTime bounds how long a single Run/RunContext call may take
+// before _tick() starts reporting it as canceled. A value of 0 (the
+// default) means unlimited. It composes with any deadline already present
+// on the context passed to RunContext: whichever fires first wins. As with
+// WithMaxSteps, enforcement only happens at _tick() call sites.
+func WithMaxWallTime(d time.Duration) SkyhookOption {
+	return func(s *Skyhook) {
+		s.maxWallTime = d
+	}
+}
@@ -0,0 +1,82 @@
+package skyhook
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/skylark"
+)
+
+// LoaderFunc resolves a module name referenced by a script's load("module",
+// "sym") statement into a StringDict of its globals.  It has the same
+// signature as skylark.Thread.Load so it can be assigned directly.
+type LoaderFunc func(thread *skylark.Thread, module string) (skylark.StringDict, error)
+
+// defaultLoad is the LoaderFunc used when no WithLoader option is given.  It
+// resolves module against s.dirs, the same list Run searches, and caches the
+// result per resolved path so that repeated loads of the same module on the
+// same Skyhook return the identical StringDict, as required by the Skylark
+// load() contract.
+func (s Skyhook) defaultLoad(thread *skylark.Thread, module string) (skylark.StringDict, error) {
+	path, b, err := s.resolve(module)
+	if err != nil {
+		return nil, err
+	}
+	return s.cache.get(thread, path, func() (skylark.StringDict, error) {
+		return skylark.ExecFile(thread, path, b, nil)
+	})
+}
+
+// loadCache memoizes the result of loading a module by its resolved path.  It
+// detects import cycles: a module that is re-entered while it is still being
+// loaded on the same thread yields an error instead of deadlocking.
+type loadCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	loading bool
+	thread  *skylark.Thread
+	done    chan struct{}
+	globals skylark.StringDict
+	err     error
+}
+
+func newLoadCache() *loadCache {
+	return &loadCache{entries: make(map[string]*cacheEntry)}
+}
+
+// get returns the cached result for path, loading it with load if this is the
+// first request for path.  Concurrent requests for the same path from other
+// threads block until the in-flight load finishes; a request for a path that
+// is already being loaded by thread is a cycle and returns an error.
+func (c *loadCache) get(thread *skylark.Thread, path string, load func() (skylark.StringDict, error)) (skylark.StringDict, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[path]; ok {
+		if e.loading {
+			if e.thread == thread {
+				c.mu.Unlock()
+				return nil, fmt.Errorf("cycle in load(%q)", path)
+			}
+			c.mu.Unlock()
+			<-e.done
+			return e.globals, e.err
+		}
+		c.mu.Unlock()
+		return e.globals, e.err
+	}
+
+	e := &cacheEntry{loading: true, thread: thread, done: make(chan struct{})}
+	c.entries[path] = e
+	c.mu.Unlock()
+
+	e.globals, e.err = load()
+
+	c.mu.Lock()
+	e.loading = false
+	close(e.done)
+	c.mu.Unlock()
+
+	return e.globals, e.err
+}
@@ -0,0 +1,88 @@
+package skyhook
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeScript(t testing.TB, dir, name, contents string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+}
+
+func TestLoadDiamond(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skyhook-diamond")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeScript(t, dir, "d.sky", "x = 1\n")
+	writeScript(t, dir, "b.sky", "load(\"d.sky\", \"x\")\ny = x + 1\n")
+	writeScript(t, dir, "c.sky", "load(\"d.sky\", \"x\")\nz = x + 2\n")
+	writeScript(t, dir, "a.sky", "load(\"b.sky\", \"y\")\nload(\"c.sky\", \"z\")\ntotal = y + z\n")
+
+	s := New([]string{dir})
+	ret, err := s.Run("a.sky", nil)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	total, ok := ret["total"].(int64)
+	if !ok || total != 5 {
+		t.Fatalf("total = %v (%T), want 5", ret["total"], ret["total"])
+	}
+
+	// d.sky is loaded via both b.sky and c.sky; both loads share the cache's
+	// single entry for it, which should hold a successful result.
+	e, ok := s.cache.entries[filepath.Join(dir, "d.sky")]
+	if !ok {
+		t.Fatal("expected d.sky to be cached")
+	}
+	if e.err != nil {
+		t.Fatalf("cached load of d.sky failed: %v", e.err)
+	}
+}
+
+func TestLoadCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skyhook-cycle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeScript(t, dir, "a.sky", "load(\"b.sky\", \"y\")\n")
+	writeScript(t, dir, "b.sky", "load(\"a.sky\", \"x\")\n")
+
+	s := New([]string{dir})
+	_, err = s.Run("a.sky", nil)
+	if err == nil {
+		t.Fatal("expected a cycle error, got nil")
+	}
+	if !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("error = %v, want a cycle error", err)
+	}
+}
+
+func TestLoadMissingModule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skyhook-missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeScript(t, dir, "a.sky", "load(\"does_not_exist.sky\", \"x\")\n")
+
+	s := New([]string{dir})
+	_, err = s.Run("a.sky", nil)
+	if err == nil {
+		t.Fatal("expected a load error, got nil")
+	}
+	if !strings.Contains(err.Error(), "does_not_exist.sky") {
+		t.Fatalf("error = %v, want it to name the missing module", err)
+	}
+}
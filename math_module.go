@@ -0,0 +1,78 @@
+package skyhook
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/google/skylark"
+)
+
+// asFloat coerces a skylark int or float value to a float64.
+func asFloat(name string, v skylark.Value) (float64, error) {
+	switch v := v.(type) {
+	case skylark.Float:
+		return float64(v), nil
+	case skylark.Int:
+		f := skylark.Float(0)
+		if i, ok := v.Int64(); ok {
+			f = skylark.Float(i)
+		} else if i, ok := v.Uint64(); ok {
+			f = skylark.Float(i)
+		}
+		return float64(f), nil
+	}
+	return 0, fmt.Errorf("%s: want a number, got %s", name, v.Type())
+}
+
+var mathModule = &skyModule{
+	name: "math",
+	members: skylark.StringDict{
+		"pi":    skylark.Float(math.Pi),
+		"e":     skylark.Float(math.E),
+		"sqrt":  mathUnary("math.sqrt", math.Sqrt),
+		"abs":   mathUnary("math.abs", math.Abs),
+		"floor": mathUnary("math.floor", math.Floor),
+		"ceil":  mathUnary("math.ceil", math.Ceil),
+		"round": mathUnary("math.round", math.Round),
+		"log":   mathUnary("math.log", math.Log),
+		"pow":   mathBinary("math.pow", math.Pow),
+		"max":   mathBinary("math.max", math.Max),
+		"min":   mathBinary("math.min", math.Min),
+	},
+}
+
+// mathUnary wraps a func(float64) float64 as a skylark builtin taking and
+// returning a single float.
+func mathUnary(name string, fn func(float64) float64) *skylark.Builtin {
+	return skylark.NewBuiltin(name, func(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+		var xv skylark.Value
+		if err := skylark.UnpackArgs(name, args, kwargs, "x", &xv); err != nil {
+			return nil, err
+		}
+		x, err := asFloat(name, xv)
+		if err != nil {
+			return nil, err
+		}
+		return skylark.Float(fn(x)), nil
+	})
+}
+
+// mathBinary wraps a func(float64, float64) float64 as a skylark builtin
+// taking two floats and returning one.
+func mathBinary(name string, fn func(float64, float64) float64) *skylark.Builtin {
+	return skylark.NewBuiltin(name, func(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+		var xv, yv skylark.Value
+		if err := skylark.UnpackArgs(name, args, kwargs, "x", &xv, "y", &yv); err != nil {
+			return nil, err
+		}
+		x, err := asFloat(name, xv)
+		if err != nil {
+			return nil, err
+		}
+		y, err := asFloat(name, yv)
+		if err != nil {
+			return nil, err
+		}
+		return skylark.Float(fn(x, y)), nil
+	})
+}
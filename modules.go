@@ -0,0 +1,238 @@
+package skyhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/skylark"
+	"github.com/google/skylark/skylarkstruct"
+)
+
+// skyModule is a frozen namespace of values exposed to scripts under a
+// single name, e.g. json.encode(...).  skylarkstruct.Module doesn't exist in
+// this skylark version (it only exports Struct/Make/FromKeywords/
+// FromStringDict/Default), so this stands in for it, along the same lines as
+// goStruct in convert.go.
+type skyModule struct {
+	name    string
+	members skylark.StringDict
+}
+
+var (
+	_ skylark.Value    = (*skyModule)(nil)
+	_ skylark.HasAttrs = (*skyModule)(nil)
+)
+
+func (m *skyModule) String() string { return fmt.Sprintf("<module %q>", m.name) }
+func (m *skyModule) Type() string   { return "module" }
+func (m *skyModule) Truth() skylark.Bool { return skylark.True }
+func (m *skyModule) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", m.Type())
+}
+
+func (m *skyModule) Freeze() {
+	for _, v := range m.members {
+		v.Freeze()
+	}
+}
+
+func (m *skyModule) Attr(name string) (skylark.Value, error) {
+	return m.members[name], nil
+}
+
+func (m *skyModule) AttrNames() []string {
+	names := make([]string, 0, len(m.members))
+	for name := range m.members {
+		names = append(names, name)
+	}
+	return names
+}
+
+// WithModules enables the named standard-library modules in every script's
+// globals, so a script can do e.g. json.encode(x) or math.sqrt(x).  The
+// available modules are "json", "time", and "math"; unknown names are
+// ignored.  Modules are frozen, so scripts can't mutate them.
+func WithModules(names ...string) SkyhookOption {
+	return func(s *Skyhook) {
+		for _, name := range names {
+			mod, ok := stdlibModules[name]
+			if !ok {
+				continue
+			}
+			mod.Freeze()
+			s.addPredeclared(name, mod)
+		}
+	}
+}
+
+var stdlibModules = map[string]*skyModule{
+	"json": jsonModule,
+	"time": timeModule,
+	"math": mathModule,
+}
+
+var jsonModule = &skyModule{
+	name: "json",
+	members: skylark.StringDict{
+		"encode": skylark.NewBuiltin("json.encode", jsonEncode),
+		"decode": skylark.NewBuiltin("json.decode", jsonDecode),
+		"indent": skylark.NewBuiltin("json.indent", jsonIndent),
+	},
+}
+
+func jsonEncode(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+	var v skylark.Value
+	if err := skylark.UnpackArgs("json.encode", args, kwargs, "x", &v); err != nil {
+		return nil, err
+	}
+	goVal, err := jsonValue(v)
+	if err != nil {
+		return nil, fmt.Errorf("json.encode: %v", err)
+	}
+	encoded, err := json.Marshal(goVal)
+	if err != nil {
+		return nil, fmt.Errorf("json.encode: %v", err)
+	}
+	return skylark.String(encoded), nil
+}
+
+// jsonValue converts a skylark value into a Go value that
+// encoding/json.Marshal can actually serialize: unlike FromValue, dicts and
+// structs become map[string]interface{} rather than
+// map[interface{}]interface{}, which json.Marshal always rejects, and every
+// nested value is converted the same way.
+func jsonValue(v skylark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case skylark.NoneType:
+		return nil, nil
+	case skylark.Bool:
+		return bool(v), nil
+	case skylark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		if i, ok := v.Uint64(); ok {
+			return i, nil
+		}
+		return nil, fmt.Errorf("integer %s overflows", v)
+	case skylark.Float:
+		return float64(v), nil
+	case skylark.String:
+		return string(v), nil
+	case skylark.Tuple:
+		vals := make([]interface{}, len(v))
+		for i, e := range v {
+			val, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = val
+		}
+		return vals, nil
+	case *skylark.List:
+		vals := make([]interface{}, 0, v.Len())
+		var e skylark.Value
+		it := v.Iterate()
+		defer it.Done()
+		for it.Next(&e) {
+			val, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		return vals, nil
+	case *skylark.Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.Keys() {
+			ks, ok := k.(skylark.String)
+			if !ok {
+				return nil, fmt.Errorf("dict key %v is not a string", k)
+			}
+			val, _, err := v.Get(k)
+			if err != nil {
+				return nil, err
+			}
+			jv, err := jsonValue(val)
+			if err != nil {
+				return nil, err
+			}
+			m[string(ks)] = jv
+		}
+		return m, nil
+	case *skylarkstruct.Struct:
+		m := make(map[string]interface{})
+		for _, name := range v.AttrNames() {
+			attr, err := v.Attr(name)
+			if err != nil {
+				return nil, err
+			}
+			jv, err := jsonValue(attr)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = jv
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot encode %s as json", v.Type())
+}
+
+func jsonDecode(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+	var s skylark.String
+	if err := skylark.UnpackArgs("json.decode", args, kwargs, "x", &s); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(s)))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("json.decode: %v", err)
+	}
+	return ToValue(decodedNumbersToValue(v))
+}
+
+// decodedNumbersToValue walks a value produced by a json.Decoder with
+// UseNumber(), converting each json.Number into an int64 if it parses
+// cleanly as one and a float64 otherwise.  Without this, every number
+// decoded from JSON comes back as encoding/json's default float64, so a
+// script indexing or doing integer arithmetic on decoded JSON would get a
+// skylark.Float where it reads an int in the source document.
+func decodedNumbersToValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		vals := make([]interface{}, len(v))
+		for i, e := range v {
+			vals[i] = decodedNumbersToValue(e)
+		}
+		return vals
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for k, e := range v {
+			m[k] = decodedNumbersToValue(e)
+		}
+		return m
+	}
+	return v
+}
+
+func jsonIndent(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+	var s skylark.String
+	prefix, indent := "", "\t"
+	if err := skylark.UnpackArgs("json.indent", args, kwargs, "str", &s, "prefix?", &prefix, "indent?", &indent); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), prefix, indent); err != nil {
+		return nil, fmt.Errorf("json.indent: %v", err)
+	}
+	return skylark.String(buf.String()), nil
+}
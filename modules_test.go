@@ -0,0 +1,77 @@
+package skyhook
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/skylark"
+)
+
+func TestJSONEncodeDict(t *testing.T) {
+	dict := &skylark.Dict{}
+	if err := dict.Set(skylark.String("a"), skylark.MakeInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dict.Set(skylark.String("b"), skylark.String("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := jsonEncode(nil, nil, skylark.Tuple{dict}, nil)
+	if err != nil {
+		t.Fatalf("json.encode(dict): %v", err)
+	}
+	s, ok := encoded.(skylark.String)
+	if !ok {
+		t.Fatalf("json.encode returned %T, want skylark.String", encoded)
+	}
+
+	decoded, err := jsonDecode(nil, nil, skylark.Tuple{s}, nil)
+	if err != nil {
+		t.Fatalf("json.decode(%s): %v", s, err)
+	}
+	got, ok := decoded.(*skylark.Dict)
+	if !ok {
+		t.Fatalf("json.decode returned %T, want *skylark.Dict", decoded)
+	}
+	a, found, err := got.Get(skylark.String("a"))
+	if err != nil || !found {
+		t.Fatalf("decoded dict missing key %q: found=%v err=%v", "a", found, err)
+	}
+	if i, ok := a.(skylark.Int); !ok || i.String() != "1" {
+		t.Fatalf("decoded a = %v, want 1", a)
+	}
+}
+
+func TestJSONEncodeList(t *testing.T) {
+	list := skylark.NewList([]skylark.Value{skylark.MakeInt(1), skylark.MakeInt(2), skylark.MakeInt(3)})
+	encoded, err := jsonEncode(nil, nil, skylark.Tuple{list}, nil)
+	if err != nil {
+		t.Fatalf("json.encode(list): %v", err)
+	}
+	if string(encoded.(skylark.String)) != "[1,2,3]" {
+		t.Fatalf("json.encode(list) = %s, want [1,2,3]", encoded)
+	}
+}
+
+func TestJSONRoundTripViaRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skyhook-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeScript(t, dir, "roundtrip.sky", "out = json.decode(json.encode({\"a\": 1, \"b\": [1, 2, 3]}))\n")
+
+	s := New([]string{dir}, WithModules("json"))
+	ret, err := s.Run("roundtrip.sky", nil)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	out, ok := ret["out"].(map[interface{}]interface{})
+	if !ok {
+		t.Fatalf("out = %v (%T), want map[interface{}]interface{}", ret["out"], ret["out"])
+	}
+	if out["a"] != int64(1) {
+		t.Fatalf("out[a] = %v, want 1", out["a"])
+	}
+}
@@ -3,51 +3,142 @@
 package skyhook
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"time"
 
 	"github.com/google/skylark"
+	"github.com/google/skylark/skylarkstruct"
 )
 
-// Skyhook is a script/plugin runner.
+// Skyhook is a script/plugin runner.  A Skyhook is safe for concurrent use:
+// the only shared state Run and RunContext mutate is the mutex-guarded load
+// and compile caches, and each call gives the script its own skylark.Thread
+// and globals.
 type Skyhook struct {
-	dirs []string
+	dirs        []string
+	loader      LoaderFunc
+	cache       *loadCache
+	compiled    *compiledCache
+	maxSteps    uint64
+	maxWallTime time.Duration
+	predeclared skylark.StringDict
+	structMode  StructMode
+}
+
+// addPredeclared adds name/val to every script's globals, unless the caller
+// supplies its own binding for name via args.
+func (s *Skyhook) addPredeclared(name string, val skylark.Value) {
+	if s.predeclared == nil {
+		s.predeclared = make(skylark.StringDict)
+	}
+	s.predeclared[name] = val
+}
+
+// SkyhookOption configures optional behavior on a Skyhook returned by New.
+type SkyhookOption func(*Skyhook)
+
+// WithLoader overrides how load("module", "sym") calls made by a running
+// script are resolved.  The default loader resolves module against the same
+// dirs passed to New, using the same search order as Run.
+func WithLoader(fn LoaderFunc) SkyhookOption {
+	return func(s *Skyhook) {
+		s.loader = fn
+	}
 }
 
 // New returns a Skyhook that looks in the given directories for plugin files to
 // run.  The directories are searched in order for files when Run is called.
-func New(dirs []string) Skyhook {
-	return Skyhook{dirs}
+func New(dirs []string, opts ...SkyhookOption) Skyhook {
+	s := Skyhook{
+		dirs:     dirs,
+		cache:    newLoadCache(),
+		compiled: newCompiledCache(defaultCompileCacheSize),
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	if s.loader == nil {
+		s.loader = s.defaultLoad
+	}
+	return s
 }
 
 // Run looks for a file with the given filename, and runs it with the given args
 // passed to the script's global namespace. The return value is all convertible
 // global variables from the script.
 func (s Skyhook) Run(filename string, args map[string]interface{}) (map[string]interface{}, error) {
+	return s.RunContext(context.Background(), filename, args)
+}
+
+// RunContext behaves like Run, but aborts the script once it next calls
+// _tick() after ctx is canceled, in addition to any WithMaxSteps/
+// WithMaxWallTime limits configured on s; see tickBuiltinName for why
+// enforcement is cooperative rather than preemptive.
+func (s Skyhook) RunContext(ctx context.Context, filename string, args map[string]interface{}) (map[string]interface{}, error) {
+	c, err := s.compiledFor(filename)
+	if err != nil {
+		return nil, err
+	}
+	return c.RunContext(ctx, args)
+}
+
+// resolve searches s.dirs, in order, for filename, returning its full path and
+// contents.
+func (s Skyhook) resolve(filename string) (string, []byte, error) {
 	for _, d := range s.dirs {
-		b, err := ioutil.ReadFile(filepath.Join(d, filename))
+		path := filepath.Join(d, filename)
+		b, err := ioutil.ReadFile(path)
 		if err == nil {
-			return s.exec(filename, b, args)
+			return path, b, nil
 		}
 	}
-	return nil, fmt.Errorf("cannot find plugin file %q in any plugin directoy", filename)
+	return "", nil, fmt.Errorf("cannot find plugin file %q in any plugin directoy", filename)
 }
 
-func (s Skyhook) exec(filename string, data []byte, args map[string]interface{}) (map[string]interface{}, error) {
+func (s Skyhook) exec(ctx context.Context, filename string, data []byte, args map[string]interface{}) (map[string]interface{}, error) {
 	thread := &skylark.Thread{
 		Print: func(_ *skylark.Thread, msg string) { fmt.Println(msg) },
+		Load:  s.loader,
 	}
-	globals, err := MakeStringDict(args)
+
+	ctx, cancel := s.withDeadline(ctx)
+	defer cancel()
+
+	globals, err := s.makeStringDict(args)
 	if err != nil {
 		return nil, err
 	}
+	for name, val := range s.predeclared {
+		if _, ok := globals[name]; !ok {
+			globals[name] = val
+		}
+	}
+	if _, ok := globals[tickBuiltinName]; !ok {
+		if s.maxSteps > 0 || s.maxWallTime > 0 || ctx.Done() != nil {
+			globals[tickBuiltinName] = newTickBuiltin(ctx, s.maxSteps)
+		}
+	}
 
-	if err := skylark.ExecFile(thread, filename, data, globals); err != nil {
+	result, err := skylark.ExecFile(thread, filename, data, globals)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
 
-	return FromStringDict(globals), nil
+	return FromStringDict(result), nil
+}
+
+// withDeadline applies s.maxWallTime, if any, on top of ctx.
+func (s Skyhook) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.maxWallTime <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.maxWallTime)
 }
 
 // ToValue attempts to convert the given value to a skylark.Value.  It supports
@@ -55,7 +146,24 @@ func (s Skyhook) exec(filename string, data []byte, args map[string]interface{})
 // skylark.Value is passed through as-is.  A []interface{} is converted with
 // MakeList, map[interface{}]interface{} is converted with MakeDict, and
 // map[interface{}]bool is converted with MakeSet.
+//
+// Anything else falls back to reflection: any slice or array becomes a
+// skylark list, any map with convertible key and value types becomes a
+// skylark dict, a func becomes a *skylark.Builtin (see WrapFunc), and a
+// struct or pointer-to-struct is snapshotted into an immutable
+// *skylarkstruct.Struct of its exported fields.  A Skyhook created with
+// WithStructMode(StructModeLive) converts structs in its own args the live,
+// method-calling way instead, except where WithStructMode says otherwise;
+// ToValue itself always uses
+// StructModeSnapshot, since it has no Skyhook to consult.  See
+// ToValueWithTag to control the struct tag used for field names.
 func ToValue(v interface{}) (skylark.Value, error) {
+	return toValueMode(v, StructModeSnapshot)
+}
+
+// toValueMode is ToValue's implementation, parameterized on the StructMode
+// to use for any struct or pointer-to-struct encountered; see WithStructMode.
+func toValueMode(v interface{}, mode StructMode) (skylark.Value, error) {
 	if val, ok := v.(skylark.Value); ok {
 		return val, nil
 	}
@@ -100,10 +208,13 @@ func ToValue(v interface{}) (skylark.Value, error) {
 		return MakeSet(v)
 	}
 
-	return nil, fmt.Errorf("type %T is not a supported skylark type", v)
+	return toValueReflect(v, DefaultStructTag, mode)
 }
 
-// FromValue converts a skylark value to a go value.
+// FromValue converts a skylark value to a go value.  Anything that isn't one
+// of the core skylark types is handed to the reflection-based converter,
+// which in particular unwraps values produced by ToValue's struct and func
+// handling back into their original Go value.
 func FromValue(v skylark.Value) (interface{}, error) {
 	switch v := v.(type) {
 	case skylark.Bool:
@@ -129,8 +240,10 @@ func FromValue(v skylark.Value) (interface{}, error) {
 		return FromDict(v)
 	case *skylark.Set:
 		return FromSet(v)
+	case *skylarkstruct.Struct:
+		return FromStruct(v)
 	}
-	return nil, fmt.Errorf("type %T is not a supported skylark type", v)
+	return fromValueReflect(v)
 }
 
 // MakeStringDict makes a StringDict from the given arg. The types supported are
@@ -147,6 +260,21 @@ func MakeStringDict(m map[string]interface{}) (skylark.StringDict, error) {
 	return dict, nil
 }
 
+// makeStringDict behaves like MakeStringDict, but converts structs according
+// to s.structMode instead of always taking ToValue's StructModeSnapshot
+// default; see WithStructMode.
+func (s Skyhook) makeStringDict(m map[string]interface{}) (skylark.StringDict, error) {
+	dict := make(skylark.StringDict, len(m))
+	for k, v := range m {
+		val, err := toValueMode(v, s.structMode)
+		if err != nil {
+			return nil, err
+		}
+		dict[k] = val
+	}
+	return dict, nil
+}
+
 // FromStringDict makes a map[string]interface{} from the given arg.  Any
 // unconvertible values are ignored.
 func FromStringDict(m skylark.StringDict) map[string]interface{} {
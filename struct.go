@@ -0,0 +1,226 @@
+package skyhook
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/skylark"
+	"github.com/google/skylark/skylarkstruct"
+)
+
+// WithStructs makes a struct(...) constructor available in every script's
+// globals, so scripts can build skylarkstruct.Struct values directly.  It is
+// opt-in because skylarkstruct isn't part of core Skylark.
+func WithStructs() SkyhookOption {
+	return func(s *Skyhook) {
+		s.addPredeclared("struct", skylarkstruct.Default)
+	}
+}
+
+// StructMode controls how a Skyhook converts a Go struct (or pointer to
+// struct) to a skylark.Value.
+type StructMode int
+
+const (
+	// StructModeSnapshot, the default, converts a struct to an immutable
+	// *skylarkstruct.Struct holding the exported field values at the time of
+	// conversion -- the shape a viable configuration-loading layer needs.
+	StructModeSnapshot StructMode = iota
+	// StructModeLive wraps the struct the way chunk0-2 originally did,
+	// before skylarkstruct integration: the value stays live, so its
+	// exported methods remain callable from the script, but it is not a
+	// *skylarkstruct.Struct and FromValue round-trips it back to the
+	// original Go value rather than a map.
+	StructModeLive
+)
+
+// WithStructMode overrides how this Skyhook's Run/RunContext convert Go
+// structs passed in as args (StructModeSnapshot, the default, if not set).
+// Structs nested inside an []interface{}, map[interface{}]interface{}, or
+// map[interface{}]bool arg are converted by ToValue, and so are always
+// snapshotted.  It's a per-Skyhook option rather than a package-level switch so that two
+// Skyhooks -- or a Skyhook and a concurrent call to the package-level
+// ToValue -- can't race over how structs get converted.
+func WithStructMode(mode StructMode) SkyhookOption {
+	return func(s *Skyhook) {
+		s.structMode = mode
+	}
+}
+
+// structToValue converts the struct or pointer-to-struct rv to a
+// skylark.Value according to mode.
+func structToValue(rv reflect.Value, tag string, mode StructMode) (skylark.Value, error) {
+	if mode == StructModeLive {
+		return &goStruct{v: rv, tag: tag}, nil
+	}
+	d, err := structFieldsToDict(rv, tag, mode)
+	if err != nil {
+		return nil, err
+	}
+	return skylarkstruct.FromStringDict(skylarkstruct.Default, d), nil
+}
+
+// structFieldsToDict converts the exported fields of the struct or
+// pointer-to-struct rv into a skylark.StringDict, using tag to rename or
+// exclude fields (DefaultStructTag if tag == "") and mode to convert any
+// nested struct fields.
+func structFieldsToDict(rv reflect.Value, tag string, mode StructMode) (skylark.StringDict, error) {
+	if tag == "" {
+		tag = DefaultStructTag
+	}
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("structFieldsToDict: %s is not a struct", rv.Type())
+	}
+
+	d := make(skylark.StringDict)
+	st := rv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		name, ok := structField(tag, st.Field(i))
+		if !ok {
+			continue
+		}
+		val, err := toValueReflect(rv.Field(i).Interface(), tag, mode)
+		if err != nil {
+			return nil, err
+		}
+		d[name] = val
+	}
+	return d, nil
+}
+
+// ToStructValue converts a Go struct (or pointer to struct) into a
+// *skylarkstruct.Struct, using tag to rename/exclude fields (DefaultStructTag
+// if tag == "").  It's equivalent to ToValue's StructModeSnapshot
+// conversion, callable directly regardless of which StructMode a Skyhook is
+// configured with.
+func ToStructValue(v interface{}, tag string) (*skylarkstruct.Struct, error) {
+	d, err := structFieldsToDict(reflect.ValueOf(v), tag, StructModeSnapshot)
+	if err != nil {
+		return nil, fmt.Errorf("ToStructValue: %v", err)
+	}
+	return skylarkstruct.FromStringDict(skylarkstruct.Default, d), nil
+}
+
+// FromStruct converts a *skylarkstruct.Struct to a map[string]interface{},
+// converting each attribute with FromValue.  Attributes FromValue can't
+// convert are omitted, as FromStringDict does for globals.
+func FromStruct(s *skylarkstruct.Struct) (map[string]interface{}, error) {
+	ret := make(map[string]interface{})
+	for _, name := range s.AttrNames() {
+		attr, err := s.Attr(name)
+		if err != nil {
+			return nil, err
+		}
+		if val, err := FromValue(attr); err == nil {
+			ret[name] = val
+		}
+	}
+	return ret, nil
+}
+
+// FromValueInto decodes a skylark value -- a *skylarkstruct.Struct or a
+// *skylark.Dict with string keys -- into dst, a pointer to a Go struct.
+// Attributes are matched to fields by name or by the DefaultStructTag
+// struct tag.  An attribute with no matching field is ignored; use
+// FromValueIntoStrict to reject those instead.
+func FromValueInto(v skylark.Value, dst interface{}) error {
+	return fromValueInto(v, dst, DefaultStructTag, false)
+}
+
+// FromValueIntoStrict behaves like FromValueInto, but returns an error if v
+// has an attribute with no matching field in dst.
+func FromValueIntoStrict(v skylark.Value, dst interface{}) error {
+	return fromValueInto(v, dst, DefaultStructTag, true)
+}
+
+func fromValueInto(v skylark.Value, dst interface{}, tag string, strict bool) error {
+	attrs, err := attrMap(v)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("FromValueInto: dst must be a pointer to a struct, got %T", dst)
+	}
+	rv = rv.Elem()
+	st := rv.Type()
+
+	matched := make(map[string]bool, len(attrs))
+	for i := 0; i < st.NumField(); i++ {
+		name, ok := structField(tag, st.Field(i))
+		if !ok {
+			continue
+		}
+		attr, ok := attrs[name]
+		if !ok {
+			continue
+		}
+		matched[name] = true
+
+		goVal, err := FromValue(attr)
+		if err != nil {
+			return fmt.Errorf("FromValueInto: field %s: %v", name, err)
+		}
+
+		field := rv.Field(i)
+		av := reflect.ValueOf(goVal)
+		if !av.IsValid() {
+			continue
+		}
+		if av.Type() != field.Type() {
+			if !av.Type().ConvertibleTo(field.Type()) {
+				return fmt.Errorf("FromValueInto: field %s: cannot assign %s to %s", name, av.Type(), field.Type())
+			}
+			av = av.Convert(field.Type())
+		}
+		field.Set(av)
+	}
+
+	if strict {
+		for name := range attrs {
+			if !matched[name] {
+				return fmt.Errorf("FromValueInto: no field for attribute %q", name)
+			}
+		}
+	}
+	return nil
+}
+
+// attrMap flattens a *skylarkstruct.Struct or *skylark.Dict with string keys
+// into a name -> value map.
+func attrMap(v skylark.Value) (map[string]skylark.Value, error) {
+	switch v := v.(type) {
+	case *skylarkstruct.Struct:
+		attrs := make(map[string]skylark.Value)
+		for _, name := range v.AttrNames() {
+			val, err := v.Attr(name)
+			if err != nil {
+				return nil, err
+			}
+			attrs[name] = val
+		}
+		return attrs, nil
+	case *skylark.Dict:
+		attrs := make(map[string]skylark.Value, v.Len())
+		for _, k := range v.Keys() {
+			ks, ok := k.(skylark.String)
+			if !ok {
+				return nil, fmt.Errorf("FromValueInto: dict key %v is not a string", k)
+			}
+			val, _, err := v.Get(k)
+			if err != nil {
+				return nil, err
+			}
+			attrs[string(ks)] = val
+		}
+		return attrs, nil
+	}
+	return nil, fmt.Errorf("FromValueInto: %T cannot be decoded into a struct", v)
+}
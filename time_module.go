@@ -0,0 +1,196 @@
+package skyhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/skylark"
+	"github.com/google/skylark/syntax"
+)
+
+var timeModule = &skyModule{
+	name: "time",
+	members: skylark.StringDict{
+		"now":            skylark.NewBuiltin("time.now", timeNow),
+		"parse_duration": skylark.NewBuiltin("time.parse_duration", timeParseDuration),
+		"from_timestamp": skylark.NewBuiltin("time.from_timestamp", timeFromTimestamp),
+	},
+}
+
+func timeNow(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+	if err := skylark.UnpackArgs("time.now", args, kwargs); err != nil {
+		return nil, err
+	}
+	return skyTime{time.Now()}, nil
+}
+
+func timeParseDuration(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+	var s string
+	if err := skylark.UnpackArgs("time.parse_duration", args, kwargs, "d", &s); err != nil {
+		return nil, err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, fmt.Errorf("time.parse_duration: %v", err)
+	}
+	return skyDuration{d}, nil
+}
+
+func timeFromTimestamp(thread *skylark.Thread, b *skylark.Builtin, args skylark.Tuple, kwargs []skylark.Tuple) (skylark.Value, error) {
+	var sec int64
+	if err := skylark.UnpackArgs("time.from_timestamp", args, kwargs, "sec", &sec); err != nil {
+		return nil, err
+	}
+	return skyTime{time.Unix(sec, 0)}, nil
+}
+
+// skyTime is a skylark.Value wrapping a Go time.Time, supporting + with a
+// skyDuration and - with another skyTime (yielding a skyDuration) or a
+// skyDuration (yielding a skyTime).
+type skyTime struct{ t time.Time }
+
+var (
+	_ skylark.Value      = skyTime{}
+	_ skylark.HasAttrs   = skyTime{}
+	_ skylark.HasBinary  = skyTime{}
+	_ skylark.Comparable = skyTime{}
+)
+
+func (t skyTime) String() string       { return t.t.Format(time.RFC3339Nano) }
+func (t skyTime) Type() string         { return "time.Time" }
+func (t skyTime) Freeze()              {}
+func (t skyTime) Truth() skylark.Bool  { return skylark.Bool(!t.t.IsZero()) }
+func (t skyTime) Hash() (uint32, error) {
+	return uint32(t.t.UnixNano()), nil
+}
+
+func (t skyTime) Attr(name string) (skylark.Value, error) {
+	switch name {
+	case "unix":
+		return skylark.MakeInt64(t.t.Unix()), nil
+	case "year":
+		return skylark.MakeInt(t.t.Year()), nil
+	case "month":
+		return skylark.MakeInt(int(t.t.Month())), nil
+	case "day":
+		return skylark.MakeInt(t.t.Day()), nil
+	case "hour":
+		return skylark.MakeInt(t.t.Hour()), nil
+	case "minute":
+		return skylark.MakeInt(t.t.Minute()), nil
+	case "second":
+		return skylark.MakeInt(t.t.Second()), nil
+	}
+	return nil, nil
+}
+
+func (t skyTime) AttrNames() []string {
+	return []string{"unix", "year", "month", "day", "hour", "minute", "second"}
+}
+
+func (t skyTime) CompareSameType(op syntax.Token, y skylark.Value, depth int) (bool, error) {
+	other := y.(skyTime)
+	switch op {
+	case syntax.LT:
+		return t.t.Before(other.t), nil
+	case syntax.LE:
+		return !t.t.After(other.t), nil
+	case syntax.GT:
+		return t.t.After(other.t), nil
+	case syntax.GE:
+		return !t.t.Before(other.t), nil
+	case syntax.EQL:
+		return t.t.Equal(other.t), nil
+	case syntax.NEQ:
+		return !t.t.Equal(other.t), nil
+	}
+	return false, fmt.Errorf("unsupported comparison on time.Time")
+}
+
+func (t skyTime) Binary(op syntax.Token, y skylark.Value, side skylark.Side) (skylark.Value, error) {
+	switch op {
+	case syntax.PLUS:
+		if d, ok := y.(skyDuration); ok {
+			return skyTime{t.t.Add(d.d)}, nil
+		}
+	case syntax.MINUS:
+		switch y := y.(type) {
+		case skyTime:
+			return skyDuration{t.t.Sub(y.t)}, nil
+		case skyDuration:
+			return skyTime{t.t.Add(-y.d)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// skyDuration is a skylark.Value wrapping a Go time.Duration, supporting +
+// and - with another skyDuration.
+type skyDuration struct{ d time.Duration }
+
+var (
+	_ skylark.Value      = skyDuration{}
+	_ skylark.HasAttrs   = skyDuration{}
+	_ skylark.HasBinary  = skyDuration{}
+	_ skylark.Comparable = skyDuration{}
+)
+
+func (d skyDuration) String() string       { return d.d.String() }
+func (d skyDuration) Type() string         { return "time.Duration" }
+func (d skyDuration) Freeze()              {}
+func (d skyDuration) Truth() skylark.Bool  { return skylark.Bool(d.d != 0) }
+func (d skyDuration) Hash() (uint32, error) {
+	return uint32(d.d), nil
+}
+
+func (d skyDuration) Attr(name string) (skylark.Value, error) {
+	switch name {
+	case "seconds":
+		return skylark.Float(d.d.Seconds()), nil
+	case "milliseconds":
+		return skylark.MakeInt64(d.d.Milliseconds()), nil
+	case "nanoseconds":
+		return skylark.MakeInt64(d.d.Nanoseconds()), nil
+	}
+	return nil, nil
+}
+
+func (d skyDuration) AttrNames() []string {
+	return []string{"seconds", "milliseconds", "nanoseconds"}
+}
+
+func (d skyDuration) CompareSameType(op syntax.Token, y skylark.Value, depth int) (bool, error) {
+	other := y.(skyDuration)
+	switch op {
+	case syntax.LT:
+		return d.d < other.d, nil
+	case syntax.LE:
+		return d.d <= other.d, nil
+	case syntax.GT:
+		return d.d > other.d, nil
+	case syntax.GE:
+		return d.d >= other.d, nil
+	case syntax.EQL:
+		return d.d == other.d, nil
+	case syntax.NEQ:
+		return d.d != other.d, nil
+	}
+	return false, fmt.Errorf("unsupported comparison on time.Duration")
+}
+
+func (d skyDuration) Binary(op syntax.Token, y skylark.Value, side skylark.Side) (skylark.Value, error) {
+	other, ok := y.(skyDuration)
+	if !ok {
+		return nil, nil
+	}
+	switch op {
+	case syntax.PLUS:
+		return skyDuration{d.d + other.d}, nil
+	case syntax.MINUS:
+		if side == skylark.Right {
+			return skyDuration{other.d - d.d}, nil
+		}
+		return skyDuration{d.d - other.d}, nil
+	}
+	return nil, nil
+}